@@ -0,0 +1,59 @@
+package libhosty
+
+import (
+	"errors"
+	"fmt"
+)
+
+// sentinel errors returned by the CommentBy*/UncommentBy* methods
+var (
+	//ErrAlredyCommentedLine is returned when asked to comment an already commented line
+	ErrAlredyCommentedLine = errors.New("line is already commented")
+
+	//ErrAlredyUncommentedLine is returned when asked to uncomment an already uncommented line
+	ErrAlredyUncommentedLine = errors.New("line is already uncommented")
+
+	//ErrNotAnAddressLine is returned when the given row is not an ADDRESS line
+	ErrNotAnAddressLine = errors.New("line is not an address line")
+
+	//ErrAddressNotFound is returned when no line matches the given address
+	ErrAddressNotFound = errors.New("address not found")
+
+	//ErrHostnameNotFound is returned when no line matches the given hostname
+	ErrHostnameNotFound = errors.New("hostname not found")
+
+	//ErrUnknown is returned when a row index does not resolve to any HostsFileLine
+	ErrUnknown = errors.New("unknown error")
+)
+
+// sentinel errors returned by the validation performed on the Add* methods
+var (
+	//ErrInvalidIP is returned when an IP address cannot be parsed
+	ErrInvalidIP = errors.New("invalid IP address")
+
+	//ErrInvalidHostname is returned when a hostname is not a valid RFC 1123 hostname
+	ErrInvalidHostname = errors.New("invalid hostname")
+
+	//ErrDuplicateEntry is returned when the hostname is already present with the same IP
+	ErrDuplicateEntry = errors.New("duplicate entry")
+)
+
+//ParseError reports a failure to parse a value passed to one of the Add*
+// methods, together with the field and value that caused it. Callers can
+// errors.Is/errors.As against the wrapped Cause (one of the ErrInvalid* sentinels).
+type ParseError struct {
+	//Field is the name of the invalid field (e.g. "ip", "hostname")
+	Field string
+	//Value is the raw value that failed validation
+	Value string
+	//Cause is the underlying sentinel error
+	Cause error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("cannot parse %s %q: %v", e.Field, e.Value, e.Cause)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,186 @@
+package libhosty
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// insertAt splices hfl into HostsFileLines at position pos, holding the lock
+// and rebuilding the lookup indexes afterwards (every index at or after pos shifts).
+func (h *HostsFile) insertAt(pos int, hfl HostsFileLine) (int, *HostsFileLine) {
+	h.Lock()
+
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(h.HostsFileLines) {
+		pos = len(h.HostsFileLines)
+	}
+
+	h.HostsFileLines = append(h.HostsFileLines, HostsFileLine{})
+	copy(h.HostsFileLines[pos+1:], h.HostsFileLines[pos:])
+	h.HostsFileLines[pos] = hfl
+
+	// rebuild while still holding the main lock: releasing it first would let
+	// a concurrent reader see the new HostsFileLines against stale ips/hosts maps
+	h.rebuildIndexes()
+
+	h.Unlock()
+
+	return pos, &h.HostsFileLines[pos]
+}
+
+//InsertHostBefore inserts a new ADDRESS line for ip/fqdn/comment immediately
+// before row. Unlike AddHost, it does not check for or clean up duplicates.
+// error is not nil if the IP cannot be parsed or row is out of range
+func (h *HostsFile) InsertHostBefore(row int, ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
+	return h.insertHostAt(row, ipRaw, fqdnRaw, comment)
+}
+
+//InsertHostAfter inserts a new ADDRESS line for ip/fqdn/comment immediately
+// after row. Unlike AddHost, it does not check for or clean up duplicates.
+// error is not nil if the IP cannot be parsed or row is out of range
+func (h *HostsFile) InsertHostAfter(row int, ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
+	return h.insertHostAt(row+1, ipRaw, fqdnRaw, comment)
+}
+
+func (h *HostsFile) insertHostAt(pos int, ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
+	if pos < 0 || pos > len(h.HostsFileLines) {
+		return -1, nil, fmt.Errorf("row %d out of range", pos)
+	}
+
+	ip := net.ParseIP(ipRaw)
+	if ip == nil {
+		return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
+	}
+
+	hostname := strings.ToLower(fqdnRaw)
+	if !isValidHostname(hostname) {
+		return -1, nil, &ParseError{Field: "hostname", Value: fqdnRaw, Cause: ErrInvalidHostname}
+	}
+
+	hfl := HostsFileLine{
+		LineType:  ADDRESS,
+		Address:   ip,
+		Hostnames: []string{hostname},
+		Comment:   comment,
+	}
+
+	hfl.Raw = lineFormatter(hfl)
+
+	idx, ptr := h.insertAt(pos, hfl)
+	return idx, ptr, nil
+}
+
+//InsertCommentBefore inserts a new COMMENT line immediately before row.
+// error is not nil if row is out of range
+func (h *HostsFile) InsertCommentBefore(row int, comment string) (int, *HostsFileLine, error) {
+	return h.insertCommentAt(row, comment)
+}
+
+//InsertCommentAfter inserts a new COMMENT line immediately after row.
+// error is not nil if row is out of range
+func (h *HostsFile) InsertCommentAfter(row int, comment string) (int, *HostsFileLine, error) {
+	return h.insertCommentAt(row+1, comment)
+}
+
+func (h *HostsFile) insertCommentAt(pos int, comment string) (int, *HostsFileLine, error) {
+	if pos < 0 || pos > len(h.HostsFileLines) {
+		return -1, nil, fmt.Errorf("row %d out of range", pos)
+	}
+
+	hfl := HostsFileLine{
+		LineType: COMMENT,
+		Raw:      "# " + comment,
+	}
+
+	hfl.Raw = lineFormatter(hfl)
+	hfl.Trimed = strings.TrimSpace(hfl.Raw)
+
+	idx, ptr := h.insertAt(pos, hfl)
+	return idx, ptr, nil
+}
+
+//InsertEmptyBefore inserts a new EMPTY line immediately before row.
+// error is not nil if row is out of range
+func (h *HostsFile) InsertEmptyBefore(row int) (int, *HostsFileLine, error) {
+	return h.insertEmptyAt(row)
+}
+
+//InsertEmptyAfter inserts a new EMPTY line immediately after row.
+// error is not nil if row is out of range
+func (h *HostsFile) InsertEmptyAfter(row int) (int, *HostsFileLine, error) {
+	return h.insertEmptyAt(row + 1)
+}
+
+func (h *HostsFile) insertEmptyAt(pos int) (int, *HostsFileLine, error) {
+	if pos < 0 || pos > len(h.HostsFileLines) {
+		return -1, nil, fmt.Errorf("row %d out of range", pos)
+	}
+
+	idx, ptr := h.insertAt(pos, HostsFileLine{LineType: EMPTY, Raw: ""})
+	return idx, ptr, nil
+}
+
+//MoveRow moves the line at index from to index to, shifting the lines in
+// between up or down to make room.
+// error is not nil if either index is out of range
+func (h *HostsFile) MoveRow(from, to int) error {
+	h.Lock()
+
+	if from < 0 || from >= len(h.HostsFileLines) || to < 0 || to >= len(h.HostsFileLines) {
+		h.Unlock()
+		return fmt.Errorf("row out of range: from=%d to=%d", from, to)
+	}
+
+	if from == to {
+		h.Unlock()
+		return nil
+	}
+
+	line := h.HostsFileLines[from]
+	h.HostsFileLines = append(h.HostsFileLines[:from], h.HostsFileLines[from+1:]...)
+
+	h.HostsFileLines = append(h.HostsFileLines, HostsFileLine{})
+	copy(h.HostsFileLines[to+1:], h.HostsFileLines[to:])
+	h.HostsFileLines[to] = line
+
+	// rebuild while still holding the main lock, same reasoning as insertAt
+	h.rebuildIndexes()
+
+	h.Unlock()
+
+	return nil
+}
+
+//BlockByComment returns the [start, end) row range delimited by two comment
+// lines equal to marker, so callers can manage a libhosty-managed block of
+// entries the same way k8s and hostess-style tools do. start is the row right
+// after the first marker, end is the row of the second marker.
+// error is not nil if a matching pair of markers cannot be found
+func (h *HostsFile) BlockByComment(marker string) (int, int, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	start := -1
+
+	for idx, hfl := range h.HostsFileLines {
+		if hfl.LineType != COMMENT {
+			continue
+		}
+
+		if strings.TrimSpace(strings.TrimPrefix(hfl.Trimed, "#")) != strings.TrimSpace(marker) {
+			continue
+		}
+
+		if start == -1 {
+			start = idx + 1
+			continue
+		}
+
+		return start, idx, nil
+	}
+
+	return -1, -1, fmt.Errorf("marker comment %q block not found", marker)
+}
@@ -0,0 +1,138 @@
+package libhosty
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsertHostBeforeAfter(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.2", "c.example.com", ""); err != nil {
+		t.Fatalf("AddHost c: %v", err)
+	}
+
+	if _, _, err := hf.InsertHostAfter(0, "127.0.0.3", "b.example.com", ""); err != nil {
+		t.Fatalf("InsertHostAfter: %v", err)
+	}
+
+	if len(hf.HostsFileLines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(hf.HostsFileLines))
+	}
+	if hf.HostsFileLines[1].Hostnames[0] != "b.example.com" {
+		t.Fatalf("expected b.example.com at row 1, got %+v", hf.HostsFileLines[1])
+	}
+
+	idx, line := hf.GetHostsFileLineByHostname("c.example.com")
+	if idx != 2 || line == nil {
+		t.Fatalf("expected c.example.com reindexed to row 2, got idx %d", idx)
+	}
+
+	if _, _, err := hf.InsertHostBefore(0, "127.0.0.4", "z.example.com", ""); err != nil {
+		t.Fatalf("InsertHostBefore: %v", err)
+	}
+	if hf.HostsFileLines[0].Hostnames[0] != "z.example.com" {
+		t.Fatalf("expected z.example.com at row 0, got %+v", hf.HostsFileLines[0])
+	}
+	if idx, _ := hf.GetHostsFileLineByHostname("c.example.com"); idx != 3 {
+		t.Fatalf("expected c.example.com reindexed to row 3, got idx %d", idx)
+	}
+}
+
+func TestInsertHostRejectsInvalidHostname(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+
+	_, _, err := hf.InsertHostAfter(0, "127.0.0.2", "not a hostname", "")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid hostname")
+	}
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+}
+
+func TestMoveRow(t *testing.T) {
+	hf := newTestHostsFile()
+
+	hosts := []struct{ ip, name string }{
+		{"127.0.0.1", "a.example.com"},
+		{"127.0.0.2", "b.example.com"},
+		{"127.0.0.3", "c.example.com"},
+	}
+	for _, h := range hosts {
+		if _, _, err := hf.AddHost(h.ip, h.name, ""); err != nil {
+			t.Fatalf("AddHost %s: %v", h.name, err)
+		}
+	}
+
+	if err := hf.MoveRow(2, 0); err != nil {
+		t.Fatalf("MoveRow: %v", err)
+	}
+
+	if hf.HostsFileLines[0].Hostnames[0] != "c.example.com" {
+		t.Fatalf("expected c.example.com at row 0 after move, got %+v", hf.HostsFileLines[0])
+	}
+
+	idx, _ := hf.GetHostsFileLineByHostname("c.example.com")
+	if idx != 0 {
+		t.Fatalf("expected index for c.example.com to be updated to 0, got %d", idx)
+	}
+}
+
+func TestBlockByComment(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddComment("managed"); err != nil {
+		t.Fatalf("AddComment start: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+	if _, _, err := hf.AddComment("managed"); err != nil {
+		t.Fatalf("AddComment end: %v", err)
+	}
+
+	start, end, err := hf.BlockByComment("managed")
+	if err != nil {
+		t.Fatalf("BlockByComment: %v", err)
+	}
+	if start != 1 || end != 2 {
+		t.Fatalf("expected block [1, 2), got [%d, %d)", start, end)
+	}
+}
+
+func TestBlockByCommentWithIndentedMarker(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+
+	content := "  # managed\n127.0.0.1 a.example.com\n  # managed\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	lines, err := ParseHostsFile(path)
+	if err != nil {
+		t.Fatalf("ParseHostsFile: %v", err)
+	}
+
+	hf := &HostsFile{
+		Config:         &HostsConfig{FilePath: path},
+		HostsFileLines: lines,
+	}
+
+	start, end, err := hf.BlockByComment("managed")
+	if err != nil {
+		t.Fatalf("BlockByComment: %v", err)
+	}
+	if start != 1 || end != 2 {
+		t.Fatalf("expected block [1, 2), got [%d, %d)", start, end)
+	}
+}
@@ -2,10 +2,11 @@
 package libhosty
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
+	"os"
 	"runtime"
 	"strings"
 	"sync"
@@ -30,12 +31,20 @@ const (
 	unixFilePath = "/etc/"
 	// defines default filename
 	hostsFileName = "hosts"
+
+	// utf8BOM is the UTF-8 byte order mark some editors (e.g. Notepad) prepend to files
+	utf8BOM = "\xEF\xBB\xBF"
 )
 
 //HostsConfig defines parameters to find hosts file.
 // FilePath is the absolute path of the hosts file (filename included)
 type HostsConfig struct {
 	FilePath string
+
+	//ForceEOL overrides the line ending used by RenderHostsFile ("\n" or "\r\n"),
+	// regardless of what was detected when the file was parsed. Leave empty to
+	// preserve the detected (or OS-native) line ending.
+	ForceEOL string
 }
 
 //HostsFileLine holds hosts file lines data
@@ -77,6 +86,24 @@ type HostsFile struct {
 
 	//HostsFileLines slice of HostsFileLine objects
 	HostsFileLines []HostsFileLine
+
+	//HasBOM reports whether the hosts file had a leading UTF-8 BOM when parsed.
+	// When true, RenderHostsFile re-emits it.
+	HasBOM bool
+
+	//EOL is the line ending detected while parsing ("\n" or "\r\n"), used by
+	// RenderHostsFile unless Config.ForceEOL is set. Empty for a new, empty file.
+	EOL string
+
+	// ipsMu protects ips
+	ipsMu sync.RWMutex
+	// ips indexes net.IP.String() to the indices of HostsFileLines holding that address
+	ips map[string][]int
+
+	// hostsMu protects hosts
+	hostsMu sync.RWMutex
+	// hosts indexes lowercase hostnames to the indices of HostsFileLines holding that hostname
+	hosts map[string][]int
 }
 
 //Init returns a new instance of a hostsfile.
@@ -104,16 +131,101 @@ func Init(conf *HostsConfig) (*HostsFile, error) {
 		HostsFileLines: make([]HostsFileLine, 0),
 	}
 
-	// parse the hosts file and load file lines
-	hf.HostsFileLines, err = ParseHostsFile(hf.Config.FilePath)
+	// parse the hosts file and load file lines, remembering the BOM/EOL found on disk
+	hf.HostsFileLines, hf.HasBOM, hf.EOL, err = parseHostsFileWithMeta(hf.Config.FilePath)
 	if err != nil {
 		return nil, err
 	}
 
+	// build the IP/hostname lookup indexes from the parsed lines
+	hf.rebuildIndexes()
+
 	//return HostsFile
 	return hf, nil
 }
 
+// rebuildIndexes discards and repopulates the ips and hosts lookup maps
+// from the current HostsFileLines. It is the only safe way to resync the
+// indexes after HostsFileLines has been reindexed (e.g. after RemoveRow).
+func (h *HostsFile) rebuildIndexes() {
+	h.ipsMu.Lock()
+	defer h.ipsMu.Unlock()
+	h.hostsMu.Lock()
+	defer h.hostsMu.Unlock()
+
+	h.ips = make(map[string][]int)
+	h.hosts = make(map[string][]int)
+
+	for idx, hfl := range h.HostsFileLines {
+		h.indexLineLocked(idx, hfl)
+	}
+}
+
+// indexLineLocked adds the given line to the ips/hosts maps.
+// Callers must hold both ipsMu and hostsMu for writing.
+func (h *HostsFile) indexLineLocked(idx int, hfl HostsFileLine) {
+	if h.ips == nil {
+		h.ips = make(map[string][]int)
+	}
+	if h.hosts == nil {
+		h.hosts = make(map[string][]int)
+	}
+
+	if hfl.LineType != ADDRESS {
+		return
+	}
+
+	if hfl.Address != nil {
+		key := hfl.Address.String()
+		h.ips[key] = append(h.ips[key], idx)
+	}
+
+	for _, hn := range hfl.Hostnames {
+		key := strings.ToLower(hn)
+		h.hosts[key] = append(h.hosts[key], idx)
+	}
+}
+
+// indexLine adds the given line to the ips/hosts maps, taking the needed locks.
+func (h *HostsFile) indexLine(idx int, hfl HostsFileLine) {
+	h.ipsMu.Lock()
+	h.hostsMu.Lock()
+	defer h.hostsMu.Unlock()
+	defer h.ipsMu.Unlock()
+
+	h.indexLineLocked(idx, hfl)
+}
+
+// indexAddHostname records that hostname now also lives at idx.
+func (h *HostsFile) indexAddHostname(hostname string, idx int) {
+	key := strings.ToLower(hostname)
+
+	h.hostsMu.Lock()
+	defer h.hostsMu.Unlock()
+
+	if h.hosts == nil {
+		h.hosts = make(map[string][]int)
+	}
+
+	h.hosts[key] = append(h.hosts[key], idx)
+}
+
+// indexRemoveHostname removes the idx entry for hostname, if present.
+func (h *HostsFile) indexRemoveHostname(hostname string, idx int) {
+	key := strings.ToLower(hostname)
+
+	h.hostsMu.Lock()
+	defer h.hostsMu.Unlock()
+
+	indices := h.hosts[key]
+	for i, v := range indices {
+		if v == idx {
+			h.hosts[key] = append(indices[:i], indices[i+1:]...)
+			break
+		}
+	}
+}
+
 //NewHostsConfig loads hosts file based on environment.
 // NewHostsConfig initialize the default file path based
 // on the OS or from a given location if a custom path is provided
@@ -156,13 +268,26 @@ func (h *HostsFile) GetHostsFileLineByRow(row int) *HostsFileLine {
 
 //GetHostsFileLineByIP returns the index of the line and a ponter to the given HostsFileLine line
 func (h *HostsFile) GetHostsFileLineByIP(ip net.IP) (int, *HostsFileLine) {
-	for idx := range h.HostsFileLines {
-		if net.IP.Equal(ip, h.HostsFileLines[idx].Address) {
-			return idx, &h.HostsFileLines[idx]
-		}
+	if ip == nil {
+		return -1, nil
 	}
 
-	return -1, nil
+	h.ipsMu.RLock()
+	indices, ok := h.ips[ip.String()]
+	h.ipsMu.RUnlock()
+
+	if !ok || len(indices) == 0 {
+		return -1, nil
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
+		return -1, nil
+	}
+	return idx, &h.HostsFileLines[idx]
 }
 
 //GetHostsFileLineByAddress returns the index of the line and a ponter to the given HostsFileLine line
@@ -173,15 +298,22 @@ func (h *HostsFile) GetHostsFileLineByAddress(address string) (int, *HostsFileLi
 
 //GetHostsFileLineByHostname returns the index of the line and a ponter to the given HostsFileLine line
 func (h *HostsFile) GetHostsFileLineByHostname(hostname string) (int, *HostsFileLine) {
-	for idx := range h.HostsFileLines {
-		for _, hn := range h.HostsFileLines[idx].Hostnames {
-			if hn == hostname {
-				return idx, &h.HostsFileLines[idx]
-			}
-		}
+	h.hostsMu.RLock()
+	indices, ok := h.hosts[strings.ToLower(hostname)]
+	h.hostsMu.RUnlock()
+
+	if !ok || len(indices) == 0 {
+		return -1, nil
 	}
 
-	return -1, nil
+	h.Lock()
+	defer h.Unlock()
+
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
+		return -1, nil
+	}
+	return idx, &h.HostsFileLines[idx]
 }
 
 //RenderHostsFile render and returns the hosts file with the lineFormatter() routine
@@ -196,7 +328,57 @@ func (h *HostsFile) RenderHostsFile() string {
 
 	// strings.Join() prevent the last line from being a new blank line
 	// as opposite to a for loop with fmt.Printf(buffer + '\n')
-	return strings.Join(sliceBuffer, "\n")
+	rendered := strings.Join(sliceBuffer, h.eol())
+
+	// re-emit the BOM if the source file had one
+	if h.HasBOM {
+		rendered = utf8BOM + rendered
+	}
+
+	return rendered
+}
+
+// eol returns the line ending to use when rendering: Config.ForceEOL if set,
+// otherwise the ending detected at parse time, falling back to the OS-native
+// ending for an empty or new file.
+func (h *HostsFile) eol() string {
+	if h.Config != nil && h.Config.ForceEOL != "" {
+		return h.Config.ForceEOL
+	}
+
+	if h.EOL != "" {
+		return h.EOL
+	}
+
+	if runtime.GOOS == "windows" {
+		return "\r\n"
+	}
+
+	return "\n"
+}
+
+// stripBOM removes a leading UTF-8 BOM from data, if present, and reports
+// whether one was found. Used while parsing to detect HasBOM.
+func stripBOM(data []byte) ([]byte, bool) {
+	bom := []byte(utf8BOM)
+
+	if bytes.HasPrefix(data, bom) {
+		return data[len(bom):], true
+	}
+
+	return data, false
+}
+
+// detectEOL returns the dominant line ending found in data: "\r\n" if CRLF
+// sequences outnumber bare LFs, "\n" otherwise. Used while parsing to populate EOL.
+func detectEOL(data []byte) string {
+	crlf := bytes.Count(data, []byte("\r\n"))
+	lf := bytes.Count(data, []byte("\n")) - crlf
+	if crlf > lf {
+		return "\r\n"
+	}
+
+	return "\n"
 }
 
 //RenderHostsFileLine render and returns the given hosts line with the lineFormatter() routine
@@ -218,17 +400,47 @@ func (h *HostsFile) SaveHostsFile() error {
 }
 
 //SaveHostsFileAs write hosts file to the given path.
+// The write uses an OS-appropriate safe strategy: an atomic temp-file-plus-rename
+// on Unix/darwin, or a truncate-and-reopen on Windows, where renaming over an
+// open file is unreliable.
 // error is not nil if something goes wrong
 func (h *HostsFile) SaveHostsFileAs(path string) error {
 	// render the file as a byte slice
 	dataBytes := []byte(h.RenderHostsFile())
 
-	// write file to disk
-	err := ioutil.WriteFile(path, dataBytes, 0644)
+	// write file to disk using a safe, OS-appropriate strategy
+	return saveFileAtomic(path, dataBytes)
+}
+
+//IsWritable reports whether the configured hosts file can be opened for writing.
+// it attempts to open the file O_WRONLY without truncating or creating it
+func (h *HostsFile) IsWritable() bool {
+	f, err := os.OpenFile(h.Config.FilePath, os.O_WRONLY, 0644)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	return true
+}
+
+//ReloadHostsFile re-parses the hosts file at the configured path and rebuilds
+// HostsFileLines and the lookup indexes, so callers using HostsFile as a
+// long-lived controller can pick up edits made externally.
+// error is not nil if something goes wrong
+func (h *HostsFile) ReloadHostsFile() error {
+	lines, hasBOM, eol, err := parseHostsFileWithMeta(h.Config.FilePath)
 	if err != nil {
 		return err
 	}
 
+	h.Lock()
+	h.HostsFileLines = lines
+	h.HasBOM = hasBOM
+	h.EOL = eol
+	h.rebuildIndexes()
+	h.Unlock()
+
 	return nil
 }
 
@@ -241,55 +453,78 @@ func (h *HostsFile) RemoveRow(row int) {
 	if row < len(h.HostsFileLines) {
 		h.HostsFileLines = append(h.HostsFileLines[:row], h.HostsFileLines[row+1:]...)
 	}
+
+	// indices shift on removal, so the cheapest correct fix is a full rebuild
+	h.rebuildIndexes()
 }
 
 //LookupByHostname check if the given fqdn exists.
 // if yes, it returns the index of the address and the associated address.
 // error is not nil if something goes wrong
 func (h *HostsFile) LookupByHostname(hostname string) (int, net.IP, error) {
-	for idx, hfl := range h.HostsFileLines {
-		for _, hn := range hfl.Hostnames {
-			if hn == hostname {
-				return idx, h.HostsFileLines[idx].Address, nil
-			}
-		}
+	h.hostsMu.RLock()
+	indices, ok := h.hosts[strings.ToLower(hostname)]
+	h.hostsMu.RUnlock()
+
+	if !ok || len(indices) == 0 {
+		return -1, nil, errors.New("Hostname not found")
 	}
 
-	return -1, nil, errors.New("Hostname not found")
+	h.Lock()
+	defer h.Unlock()
+
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
+		return -1, nil, errors.New("Hostname not found")
+	}
+	return idx, h.HostsFileLines[idx].Address, nil
 }
 
 //AddHostRaw add the given ip/fqdn/comment pair
-// this is different from AddHost because it does not take care of duplicates
-// this just append the new entry to the hosts file
+// this is different from AddHost because it does not merge with or clean up
+// a previous entry for the same hostname, it just appends a new entry to the
+// hosts file. It still rejects an exact duplicate (the hostname already
+// pointing at the same IP) with ErrDuplicateEntry, to guard bulk/raw import
+// callers against accidentally inserting the same line twice.
 func (h *HostsFile) AddHostRaw(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
 	// hostname to lowercase
 	hostname := strings.ToLower(fqdnRaw)
 	// parse ip to net.IP
 	ip := net.ParseIP(ipRaw)
 
-	// if we have a valid IP
-	if ip != nil {
-		// create a new hosts line
-		hfl := HostsFileLine{
-			LineType:    ADDRESS,
-			Address:     ip,
-			Hostnames:   []string{hostname},
-			Comment:     comment,
-			IsCommented: false,
-		}
+	if ip == nil {
+		return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
+	}
 
-		// append to hosts
-		h.HostsFileLines = append(h.HostsFileLines, hfl)
+	if !isValidHostname(hostname) {
+		return -1, nil, &ParseError{Field: "hostname", Value: fqdnRaw, Cause: ErrInvalidHostname}
+	}
 
-		// get index
-		idx := len(h.HostsFileLines) - 1
+	// reject an exact duplicate: same hostname already pointing at the same IP
+	if _, addr, err := h.LookupByHostname(hostname); err == nil && net.IP.Equal(addr, ip) {
+		return -1, nil, ErrDuplicateEntry
+	}
 
-		// return created entry
-		return idx, &h.HostsFileLines[idx], nil
+	// create a new hosts line
+	hfl := HostsFileLine{
+		LineType:    ADDRESS,
+		Address:     ip,
+		Hostnames:   []string{hostname},
+		Comment:     comment,
+		IsCommented: false,
 	}
 
-	// return error
-	return -1, nil, fmt.Errorf("Cannot parse IP address %s", ipRaw)
+	// append to hosts
+	h.HostsFileLines = append(h.HostsFileLines, hfl)
+
+	// get index
+	idx := len(h.HostsFileLines) - 1
+
+	// keep lookup indexes in sync
+	h.indexLine(idx, hfl)
+
+	// return created entry
+	return idx, &h.HostsFileLines[idx], nil
 }
 
 //AddHost add the given ip/fqdn/comment pair, cleanup is done for previous entry.
@@ -301,6 +536,14 @@ func (h *HostsFile) AddHost(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine
 	// parse ip to net.IP
 	ip := net.ParseIP(ipRaw)
 
+	if ip == nil {
+		return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
+	}
+
+	if !isValidHostname(hostname) {
+		return -1, nil, &ParseError{Field: "hostname", Value: fqdnRaw, Cause: ErrInvalidHostname}
+	}
+
 	// if we have a valid IP
 	if ip != nil {
 		//check if we alredy have the fqdn
@@ -322,6 +565,7 @@ func (h *HostsFile) AddHost(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine
 						h.Lock()
 						h.HostsFileLines[idx].Hostnames = append(h.HostsFileLines[idx].Hostnames[:hostIdx], h.HostsFileLines[idx].Hostnames[hostIdx+1:]...)
 						h.Unlock()
+						h.indexRemoveHostname(hostname, idx)
 					}
 
 					//remove the line if there are no more hostnames (other than the actual one)
@@ -338,6 +582,7 @@ func (h *HostsFile) AddHost(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine
 				h.Lock()
 				h.HostsFileLines[idx].Hostnames = append(h.HostsFileLines[idx].Hostnames, hostname)
 				h.Unlock()
+				h.indexAddHostname(hostname, idx)
 
 				// handle comment
 				if comment != "" {
@@ -368,12 +613,15 @@ func (h *HostsFile) AddHost(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine
 		// get index
 		idx := len(h.HostsFileLines) - 1
 
+		// keep lookup indexes in sync
+		h.indexLine(idx, hfl)
+
 		// return created entry
 		return idx, &h.HostsFileLines[idx], nil
 	}
 
-	// return error
-	return -1, nil, fmt.Errorf("Cannot parse IP address %s", ipRaw)
+	// unreachable: ip has already been validated above
+	return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
 }
 
 //AddComment adds a new line of type comment with the given comment.
@@ -389,6 +637,7 @@ func (h *HostsFile) AddComment(comment string) (int, *HostsFileLine, error) {
 	}
 
 	hfl.Raw = lineFormatter(hfl)
+	hfl.Trimed = strings.TrimSpace(hfl.Raw)
 
 	h.HostsFileLines = append(h.HostsFileLines, hfl)
 	idx := len(h.HostsFileLines) - 1
@@ -436,23 +685,31 @@ func (h *HostsFile) CommentByRow(row int) error {
 
 //CommentByIP set the IsCommented bit for the given address to true
 func (h *HostsFile) CommentByIP(ip net.IP) error {
-	h.Lock()
-	defer h.Unlock()
+	if ip == nil {
+		return ErrAddressNotFound
+	}
 
-	for idx, hfl := range h.HostsFileLines {
-		if net.IP.Equal(ip, hfl.Address) {
-			if h.HostsFileLines[idx].IsCommented != true {
-				h.HostsFileLines[idx].IsCommented = true
-				return nil
-			}
+	h.ipsMu.RLock()
+	indices, ok := h.ips[ip.String()]
+	h.ipsMu.RUnlock()
 
-			return ErrAlredyCommentedLine
-		}
+	if !ok || len(indices) == 0 {
+		return ErrAddressNotFound
+	}
 
+	h.Lock()
+	defer h.Unlock()
+
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
 		return ErrAddressNotFound
 	}
+	if h.HostsFileLines[idx].IsCommented != true {
+		h.HostsFileLines[idx].IsCommented = true
+		return nil
+	}
 
-	return ErrUnknown
+	return ErrAlredyCommentedLine
 }
 
 //CommentByAddress set the IsCommented bit for the given address as string to false
@@ -464,25 +721,27 @@ func (h *HostsFile) CommentByAddress(address string) error {
 
 //CommentByHostname set the IsCommented bit for the given hostname to true
 func (h *HostsFile) CommentByHostname(hostname string) error {
-	h.Lock()
-	defer h.Unlock()
+	h.hostsMu.RLock()
+	indices, ok := h.hosts[strings.ToLower(hostname)]
+	h.hostsMu.RUnlock()
 
-	for idx := range h.HostsFileLines {
-		for _, hn := range h.HostsFileLines[idx].Hostnames {
-			if hn == hostname {
-				if h.HostsFileLines[idx].IsCommented != true {
-					h.HostsFileLines[idx].IsCommented = true
-					return nil
-				}
+	if !ok || len(indices) == 0 {
+		return ErrHostnameNotFound
+	}
 
-				return ErrAlredyCommentedLine
-			}
-		}
+	h.Lock()
+	defer h.Unlock()
 
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
 		return ErrHostnameNotFound
 	}
+	if h.HostsFileLines[idx].IsCommented != true {
+		h.HostsFileLines[idx].IsCommented = true
+		return nil
+	}
 
-	return ErrUnknown
+	return ErrAlredyCommentedLine
 }
 
 //UncommentByRow set the IsCommented bit for the given row to false
@@ -508,23 +767,31 @@ func (h *HostsFile) UncommentByRow(row int) error {
 
 //UncommentByIP set the IsCommented bit for the given address to false
 func (h *HostsFile) UncommentByIP(ip net.IP) error {
-	h.Lock()
-	defer h.Unlock()
+	if ip == nil {
+		return ErrAddressNotFound
+	}
 
-	for idx, hfl := range h.HostsFileLines {
-		if net.IP.Equal(ip, hfl.Address) {
-			if h.HostsFileLines[idx].IsCommented != false {
-				h.HostsFileLines[idx].IsCommented = false
-				return nil
-			}
+	h.ipsMu.RLock()
+	indices, ok := h.ips[ip.String()]
+	h.ipsMu.RUnlock()
 
-			return ErrAlredyUncommentedLine
-		}
+	if !ok || len(indices) == 0 {
+		return ErrAddressNotFound
+	}
 
-		return ErrNotAnAddressLine
+	h.Lock()
+	defer h.Unlock()
+
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
+		return ErrAddressNotFound
+	}
+	if h.HostsFileLines[idx].IsCommented != false {
+		h.HostsFileLines[idx].IsCommented = false
+		return nil
 	}
 
-	return ErrUnknown
+	return ErrAlredyUncommentedLine
 }
 
 //UncommentByAddress set the IsCommented bit for the given address as string to false
@@ -536,23 +803,25 @@ func (h *HostsFile) UncommentByAddress(address string) error {
 
 //UncommentByHostname set the IsCommented bit for the given hostname to false
 func (h *HostsFile) UncommentByHostname(hostname string) error {
-	h.Lock()
-	defer h.Unlock()
+	h.hostsMu.RLock()
+	indices, ok := h.hosts[strings.ToLower(hostname)]
+	h.hostsMu.RUnlock()
 
-	for idx := range h.HostsFileLines {
-		for _, hn := range h.HostsFileLines[idx].Hostnames {
-			if hn == hostname {
-				if h.HostsFileLines[idx].IsCommented != false {
-					h.HostsFileLines[idx].IsCommented = false
-					return nil
-				}
+	if !ok || len(indices) == 0 {
+		return ErrHostnameNotFound
+	}
 
-				return ErrAlredyUncommentedLine
-			}
-		}
+	h.Lock()
+	defer h.Unlock()
 
+	idx := indices[0]
+	if idx >= len(h.HostsFileLines) {
 		return ErrHostnameNotFound
 	}
+	if h.HostsFileLines[idx].IsCommented != false {
+		h.HostsFileLines[idx].IsCommented = false
+		return nil
+	}
 
-	return ErrUnknown
+	return ErrAlredyUncommentedLine
 }
@@ -0,0 +1,106 @@
+package libhosty
+
+import (
+	"sync"
+	"testing"
+)
+
+func newTestHostsFile() *HostsFile {
+	return &HostsFile{
+		Config:         &HostsConfig{},
+		HostsFileLines: []HostsFileLine{},
+	}
+}
+
+func TestAddHostIndexesLookup(t *testing.T) {
+	hf := newTestHostsFile()
+
+	idx, _, err := hf.AddHost("127.0.0.1", "example.com", "")
+	if err != nil {
+		t.Fatalf("AddHost returned error: %v", err)
+	}
+
+	gotIdx, line := hf.GetHostsFileLineByHostname("example.com")
+	if gotIdx != idx || line == nil {
+		t.Fatalf("GetHostsFileLineByHostname: got idx %d, want %d", gotIdx, idx)
+	}
+
+	gotIdx, line = hf.GetHostsFileLineByAddress("127.0.0.1")
+	if gotIdx != idx || line == nil {
+		t.Fatalf("GetHostsFileLineByAddress: got idx %d, want %d", gotIdx, idx)
+	}
+}
+
+func TestRemoveRowRebuildsIndexes(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.2", "b.example.com", ""); err != nil {
+		t.Fatalf("AddHost b: %v", err)
+	}
+
+	hf.RemoveRow(0)
+
+	idx, line := hf.GetHostsFileLineByHostname("b.example.com")
+	if idx != 0 || line == nil || line.Address.String() != "127.0.0.2" {
+		t.Fatalf("index for b.example.com stale after RemoveRow: idx=%d line=%+v", idx, line)
+	}
+
+	if idx, _ := hf.GetHostsFileLineByHostname("a.example.com"); idx != -1 {
+		t.Fatalf("a.example.com should no longer be indexed after removal, got idx %d", idx)
+	}
+}
+
+func TestAddHostUpsertsExistingHostname(t *testing.T) {
+	hf := newTestHostsFile()
+
+	idx, _, err := hf.AddHost("127.0.0.1", "example.com", "first")
+	if err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+
+	idx2, line, err := hf.AddHost("127.0.0.1", "example.com", "second")
+	if err != nil {
+		t.Fatalf("AddHost update: %v", err)
+	}
+
+	if idx2 != idx {
+		t.Fatalf("expected same index on upsert, got %d want %d", idx2, idx)
+	}
+	if line.Comment != "second" {
+		t.Fatalf("expected comment to be updated, got %q", line.Comment)
+	}
+}
+
+func TestConcurrentGetHostsFileLineByIPAndRemoveRow(t *testing.T) {
+	hf := newTestHostsFile()
+
+	for i := 0; i < 50; i++ {
+		if _, _, err := hf.AddHost("127.0.0.1", "keep.example.com", ""); err != nil {
+			t.Fatalf("AddHost: %v", err)
+		}
+		if _, _, err := hf.AddHost("127.0.0.2", "victim.example.com", ""); err != nil {
+			t.Fatalf("AddHost: %v", err)
+		}
+
+		ip := hf.HostsFileLines[0].Address
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hf.GetHostsFileLineByIP(ip)
+		}()
+		go func() {
+			defer wg.Done()
+			hf.RemoveRow(1)
+		}()
+		wg.Wait()
+
+		hf.HostsFileLines = nil
+		hf.ips = nil
+		hf.hosts = nil
+	}
+}
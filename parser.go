@@ -0,0 +1,134 @@
+package libhosty
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+//ParseHostsFile reads and parses the hosts file at path into a slice of
+// HostsFileLine. A missing file is treated as empty (new), not an error.
+func ParseHostsFile(path string) ([]HostsFileLine, error) {
+	lines, _, _, err := parseHostsFileWithMeta(path)
+	return lines, err
+}
+
+// parseHostsFileWithMeta is the real parse path: it reads path, strips a
+// leading UTF-8 BOM and detects the dominant line ending before splitting
+// into lines, so callers (Init, ReloadHostsFile) can remember both on HostsFile.
+func parseHostsFileWithMeta(path string) (lines []HostsFileLine, hasBOM bool, eol string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []HostsFileLine{}, false, "", nil
+		}
+
+		return nil, false, "", err
+	}
+
+	data, hasBOM = stripBOM(data)
+	if len(data) == 0 {
+		// nothing to detect: leave eol empty so rendering uses the OS-native ending
+		return []HostsFileLine{}, hasBOM, "", nil
+	}
+	eol = detectEOL(data)
+
+	text := strings.TrimSuffix(string(data), eol)
+	if text == "" {
+		return []HostsFileLine{}, hasBOM, eol, nil
+	}
+
+	rawLines := strings.Split(text, eol)
+	lines = make([]HostsFileLine, 0, len(rawLines))
+
+	for i, raw := range rawLines {
+		lines = append(lines, parseLine(i+1, raw))
+	}
+
+	return lines, hasBOM, eol, nil
+}
+
+// parseLine classifies and parses a single raw hosts file line.
+func parseLine(lineNumber int, raw string) HostsFileLine {
+	trimmed := strings.TrimSpace(raw)
+
+	hfl := HostsFileLine{
+		LineNumber: lineNumber,
+		Raw:        raw,
+		Trimed:     trimmed,
+	}
+
+	if trimmed == "" {
+		hfl.LineType = EMPTY
+		return hfl
+	}
+
+	isCommented := strings.HasPrefix(trimmed, "#")
+	content := trimmed
+	if isCommented {
+		content = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+	}
+
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		hfl.LineType = COMMENT
+		return hfl
+	}
+
+	ip := net.ParseIP(fields[0])
+	if ip == nil {
+		hfl.LineType = COMMENT
+		return hfl
+	}
+
+	parts := strings.SplitN(content, "#", 2)
+
+	hfl.LineType = ADDRESS
+	hfl.IsCommented = isCommented
+	hfl.Address = ip
+	hfl.Parts = parts
+	hfl.Hostnames = strings.Fields(parts[0])[1:]
+
+	if len(parts) > 1 {
+		hfl.Comment = strings.TrimSpace(parts[1])
+	}
+
+	return hfl
+}
+
+//lineFormatter renders a single HostsFileLine back to its raw text form.
+func lineFormatter(hfl HostsFileLine) string {
+	switch hfl.LineType {
+	case EMPTY:
+		return ""
+	case ADDRESS:
+		return formatAddressLine(hfl)
+	default:
+		// COMMENT and UNKNOWN lines are rendered verbatim
+		return hfl.Raw
+	}
+}
+
+func formatAddressLine(hfl HostsFileLine) string {
+	var b strings.Builder
+
+	if hfl.IsCommented {
+		b.WriteString("# ")
+	}
+
+	if hfl.Address != nil {
+		b.WriteString(hfl.Address.String())
+	}
+
+	for _, hn := range hfl.Hostnames {
+		b.WriteString(" ")
+		b.WriteString(hn)
+	}
+
+	if hfl.Comment != "" {
+		b.WriteString(" # ")
+		b.WriteString(hfl.Comment)
+	}
+
+	return b.String()
+}
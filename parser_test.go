@@ -0,0 +1,62 @@
+package libhosty
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAndRenderPreservesBOMAndCRLF(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+
+	original := utf8BOM + "127.0.0.1 example.com\r\n::1 localhost\r\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	lines, hasBOM, eol, err := parseHostsFileWithMeta(path)
+	if err != nil {
+		t.Fatalf("parseHostsFileWithMeta: %v", err)
+	}
+	if !hasBOM {
+		t.Fatalf("expected hasBOM to be true")
+	}
+	if eol != "\r\n" {
+		t.Fatalf("expected eol to be detected as CRLF, got %q", eol)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 parsed lines, got %d", len(lines))
+	}
+
+	hf := &HostsFile{
+		Config:         &HostsConfig{FilePath: path},
+		HostsFileLines: lines,
+		HasBOM:         hasBOM,
+		EOL:            eol,
+	}
+
+	rendered := hf.RenderHostsFile()
+	if want := utf8BOM + "127.0.0.1 example.com\r\n::1 localhost"; rendered != want {
+		t.Fatalf("RenderHostsFile = %q, want %q", rendered, want)
+	}
+}
+
+func TestForceEOLOverridesDetectedEnding(t *testing.T) {
+	hf := newTestHostsFile()
+	hf.Config.ForceEOL = "\n"
+	hf.EOL = "\r\n"
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.2", "b.example.com", ""); err != nil {
+		t.Fatalf("AddHost b: %v", err)
+	}
+
+	rendered := hf.RenderHostsFile()
+	want := "127.0.0.1 a.example.com\n127.0.0.2 b.example.com"
+	if rendered != want {
+		t.Fatalf("RenderHostsFile = %q, want %q", rendered, want)
+	}
+}
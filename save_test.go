@@ -0,0 +1,67 @@
+package libhosty
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveHostsFileAsIsAtomicAndPreservesContent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+
+	if err := os.WriteFile(path, []byte("127.0.0.1 old.example.com\n"), 0644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	hf := newTestHostsFile()
+	hf.Config.FilePath = path
+
+	if _, _, err := hf.AddHost("127.0.0.1", "new.example.com", ""); err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+
+	if err := hf.SaveHostsFileAs(path); err != nil {
+		t.Fatalf("SaveHostsFileAs: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	for _, e := range entries {
+		if e.Name() != "hosts" {
+			t.Fatalf("leftover temp file not cleaned up: %s", e.Name())
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+
+	if got, want := string(data), "127.0.0.1 new.example.com"; got != want {
+		t.Fatalf("saved content = %q, want %q", got, want)
+	}
+}
+
+func TestIsWritable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+
+	if err := os.WriteFile(path, []byte(""), 0644); err != nil {
+		t.Fatalf("seed file: %v", err)
+	}
+
+	hf := newTestHostsFile()
+	hf.Config.FilePath = path
+
+	if !hf.IsWritable() {
+		t.Fatalf("expected IsWritable to be true for a writable file")
+	}
+
+	hf.Config.FilePath = filepath.Join(dir, "does-not-exist")
+	if hf.IsWritable() {
+		t.Fatalf("expected IsWritable to be false for a missing file")
+	}
+}
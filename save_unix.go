@@ -0,0 +1,75 @@
+// +build !windows
+
+package libhosty
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// saveFileAtomic writes data to path atomically: it writes to a sibling
+// temp file in the same directory, fsyncs it, then renames it over path.
+// The original file's mode, uid and gid are copied onto the new file when path
+// already exists; a permission error while copying them is ignored.
+func saveFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	mode := os.FileMode(0644)
+	uid, gid := -1, -1
+
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode()
+		if st, ok := info.Sys().(*syscall.Stat_t); ok {
+			uid = int(st.Uid)
+			gid = int(st.Gid)
+		}
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".hosts-tmp-")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	// chmod/chown are best-effort: a process that can write the target file
+	// but does not own it (e.g. a shared, world-writable managed file owned
+	// by a different uid, common in containers) gets EPERM here even though
+	// the write itself is allowed, so only abort on unexpected errors.
+	if err := os.Chmod(tmpPath, mode); err != nil && !os.IsPermission(err) {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if uid != -1 {
+		if err := os.Chown(tmpPath, uid, gid); err != nil && !os.IsPermission(err) {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+// +build windows
+
+package libhosty
+
+import "os"
+
+// saveFileAtomic writes data to path. Renaming over an open file is
+// unreliable on Windows, so instead of a temp-file-plus-rename strategy the
+// target is truncated and reopened O_RDWR for writing.
+func saveFileAtomic(path string, data []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Truncate(path, 0); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
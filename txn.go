@@ -0,0 +1,218 @@
+package libhosty
+
+import (
+	"net"
+	"strings"
+)
+
+//Txn is a buffered, all-or-nothing transaction over a HostsFile. It exposes
+// a subset of the HostsFile mutation surface (AddHost, AddHostRaw, RemoveRow,
+// CommentByHostname, UncommentByHostname) against a private copy of
+// HostsFileLines, so intermediate state is never visible to concurrent
+// readers of the parent HostsFile until Commit is called.
+type Txn struct {
+	hf    *HostsFile
+	lines []HostsFileLine
+}
+
+//Begin starts a new Txn against h, seeded with a deep copy of the current
+// HostsFileLines.
+func (h *HostsFile) Begin() *Txn {
+	h.Lock()
+	defer h.Unlock()
+
+	lines := make([]HostsFileLine, len(h.HostsFileLines))
+	copy(lines, h.HostsFileLines)
+	// copy is shallow: Hostnames/Parts still alias the parent's backing
+	// arrays, so an in-place edit (e.g. AddHost re-pointing a hostname that
+	// shares a multi-hostname line) would mutate the parent before Commit
+	for i, hfl := range lines {
+		if hfl.Hostnames != nil {
+			lines[i].Hostnames = append([]string(nil), hfl.Hostnames...)
+		}
+		if hfl.Parts != nil {
+			lines[i].Parts = append([]string(nil), hfl.Parts...)
+		}
+	}
+
+	return &Txn{
+		hf:    h,
+		lines: lines,
+	}
+}
+
+//Commit swaps the transaction's buffered lines into the parent HostsFile
+// under the lock and rebuilds its lookup indexes.
+func (t *Txn) Commit() {
+	t.hf.Lock()
+	t.hf.HostsFileLines = t.lines
+	// rebuild while still holding the main lock: releasing it first would let
+	// a concurrent reader see the new HostsFileLines against stale ips/hosts maps
+	t.hf.rebuildIndexes()
+	t.hf.Unlock()
+}
+
+//Rollback discards the transaction's buffered changes. The parent HostsFile
+// is left untouched.
+func (t *Txn) Rollback() {
+	t.lines = nil
+}
+
+// lookupByHostname scans the buffered lines for hostname, the same way
+// HostsFile.LookupByHostname does for the committed lines.
+func (t *Txn) lookupByHostname(hostname string) (int, net.IP) {
+	for idx, hfl := range t.lines {
+		for _, hn := range hfl.Hostnames {
+			if strings.EqualFold(hn, hostname) {
+				return idx, hfl.Address
+			}
+		}
+	}
+
+	return -1, nil
+}
+
+//AddHost buffers adding the given ip/fqdn/comment pair, with the same
+// cleanup-of-previous-entry semantics as HostsFile.AddHost.
+// error is not nil if the IP cannot be parsed
+func (t *Txn) AddHost(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
+	hostname := strings.ToLower(fqdnRaw)
+	ip := net.ParseIP(ipRaw)
+
+	if ip == nil {
+		return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
+	}
+
+	if !isValidHostname(hostname) {
+		return -1, nil, &ParseError{Field: "hostname", Value: fqdnRaw, Cause: ErrInvalidHostname}
+	}
+
+	if idx, addr := t.lookupByHostname(hostname); idx != -1 {
+		if net.IP.Equal(addr, ip) {
+			if comment != "" {
+				t.lines[idx].Comment = comment
+			}
+			return idx, &t.lines[idx], nil
+		}
+
+		for hostIdx, hn := range t.lines[idx].Hostnames {
+			if hn != hostname {
+				continue
+			}
+
+			if len(t.lines[idx].Hostnames) > 1 {
+				t.lines[idx].Hostnames = append(t.lines[idx].Hostnames[:hostIdx], t.lines[idx].Hostnames[hostIdx+1:]...)
+			}
+
+			if len(t.lines[idx].Hostnames) <= 1 {
+				t.RemoveRow(idx)
+			}
+		}
+	}
+
+	for idx, hfl := range t.lines {
+		if net.IP.Equal(hfl.Address, ip) {
+			t.lines[idx].Hostnames = append(t.lines[idx].Hostnames, hostname)
+
+			if comment != "" {
+				t.lines[idx].Comment = comment
+			}
+
+			return idx, &t.lines[idx], nil
+		}
+	}
+
+	hfl := HostsFileLine{
+		LineType:  ADDRESS,
+		Address:   ip,
+		Hostnames: []string{hostname},
+		Comment:   comment,
+	}
+	hfl.Raw = lineFormatter(hfl)
+
+	t.lines = append(t.lines, hfl)
+	idx := len(t.lines) - 1
+
+	return idx, &t.lines[idx], nil
+}
+
+//AddHostRaw buffers adding the given ip/fqdn/comment pair without merging with
+// a previous entry, with the same semantics as HostsFile.AddHostRaw: an exact
+// duplicate (same hostname and IP) is rejected with ErrDuplicateEntry.
+// error is not nil if the IP cannot be parsed
+func (t *Txn) AddHostRaw(ipRaw, fqdnRaw, comment string) (int, *HostsFileLine, error) {
+	hostname := strings.ToLower(fqdnRaw)
+	ip := net.ParseIP(ipRaw)
+
+	if ip == nil {
+		return -1, nil, &ParseError{Field: "ip", Value: ipRaw, Cause: ErrInvalidIP}
+	}
+
+	if !isValidHostname(hostname) {
+		return -1, nil, &ParseError{Field: "hostname", Value: fqdnRaw, Cause: ErrInvalidHostname}
+	}
+
+	if idx, addr := t.lookupByHostname(hostname); idx != -1 && net.IP.Equal(addr, ip) {
+		return -1, nil, ErrDuplicateEntry
+	}
+
+	hfl := HostsFileLine{
+		LineType:  ADDRESS,
+		Address:   ip,
+		Hostnames: []string{hostname},
+		Comment:   comment,
+	}
+	hfl.Raw = lineFormatter(hfl)
+
+	t.lines = append(t.lines, hfl)
+	idx := len(t.lines) - 1
+
+	return idx, &t.lines[idx], nil
+}
+
+//RemoveRow buffers removing the line at row from the transaction.
+func (t *Txn) RemoveRow(row int) {
+	if row < len(t.lines) {
+		t.lines = append(t.lines[:row], t.lines[row+1:]...)
+	}
+}
+
+//CommentByHostname buffers setting the IsCommented bit to true for hostname's line.
+func (t *Txn) CommentByHostname(hostname string) error {
+	idx, _ := t.lookupByHostname(hostname)
+	if idx == -1 {
+		return ErrHostnameNotFound
+	}
+
+	if t.lines[idx].IsCommented {
+		return ErrAlredyCommentedLine
+	}
+
+	t.lines[idx].IsCommented = true
+	return nil
+}
+
+//UncommentByHostname buffers setting the IsCommented bit to false for hostname's line.
+func (t *Txn) UncommentByHostname(hostname string) error {
+	idx, _ := t.lookupByHostname(hostname)
+	if idx == -1 {
+		return ErrHostnameNotFound
+	}
+
+	if !t.lines[idx].IsCommented {
+		return ErrAlredyUncommentedLine
+	}
+
+	t.lines[idx].IsCommented = false
+	return nil
+}
+
+//CommitAndSave commits the transaction and immediately persists the result to
+// the configured hosts file path via the atomic write path, giving callers
+// all-or-nothing semantics for a batch of edits, e.g. replacing an entire
+// libhosty-managed block with a new set of entries in one call.
+// error is not nil if the save fails; the commit itself has already taken effect
+func (t *Txn) CommitAndSave() error {
+	t.Commit()
+	return t.hf.SaveHostsFileAs(t.hf.Config.FilePath)
+}
@@ -0,0 +1,115 @@
+package libhosty
+
+import "testing"
+
+func TestTxnCommitAppliesBufferedEdits(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+
+	txn := hf.Begin()
+	if _, _, err := txn.AddHost("127.0.0.2", "b.example.com", ""); err != nil {
+		t.Fatalf("Txn.AddHost: %v", err)
+	}
+
+	if idx, _ := hf.GetHostsFileLineByHostname("b.example.com"); idx != -1 {
+		t.Fatalf("parent HostsFile should not see uncommitted Txn edits")
+	}
+
+	txn.Commit()
+
+	idx, line := hf.GetHostsFileLineByHostname("b.example.com")
+	if idx == -1 || line == nil {
+		t.Fatalf("expected b.example.com to be visible after Commit")
+	}
+}
+
+func TestTxnRollbackDiscardsEdits(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost: %v", err)
+	}
+
+	txn := hf.Begin()
+	if _, _, err := txn.AddHost("127.0.0.2", "b.example.com", ""); err != nil {
+		t.Fatalf("Txn.AddHost: %v", err)
+	}
+	txn.Rollback()
+
+	if len(hf.HostsFileLines) != 1 {
+		t.Fatalf("expected parent HostsFile untouched by rollback, got %d lines", len(hf.HostsFileLines))
+	}
+}
+
+func TestTxnAddHostRawValidatesHostname(t *testing.T) {
+	hf := newTestHostsFile()
+	txn := hf.Begin()
+
+	_, _, err := txn.AddHostRaw("127.0.0.1", "not a hostname", "")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid hostname")
+	}
+	if _, ok := err.(*ParseError); !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+}
+
+func TestTxnAddHostRawRejectsDuplicate(t *testing.T) {
+	hf := newTestHostsFile()
+	txn := hf.Begin()
+
+	if _, _, err := txn.AddHostRaw("127.0.0.1", "example.com", ""); err != nil {
+		t.Fatalf("first AddHostRaw: %v", err)
+	}
+
+	_, _, err := txn.AddHostRaw("127.0.0.1", "example.com", "")
+	if err != ErrDuplicateEntry {
+		t.Fatalf("expected ErrDuplicateEntry, got %v", err)
+	}
+}
+
+func TestTxnBeginDeepCopiesMultiHostnameLines(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHost("127.0.0.1", "a.example.com", ""); err != nil {
+		t.Fatalf("AddHost a: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.1", "b.example.com", ""); err != nil {
+		t.Fatalf("AddHost b: %v", err)
+	}
+	if _, _, err := hf.AddHost("127.0.0.1", "c.example.com", ""); err != nil {
+		t.Fatalf("AddHost c: %v", err)
+	}
+
+	idx, line := hf.GetHostsFileLineByHostname("a.example.com")
+	if idx == -1 || len(line.Hostnames) != 3 {
+		t.Fatalf("expected one line with 3 hostnames, got %+v", line)
+	}
+	want := append([]string(nil), hf.HostsFileLines[idx].Hostnames...)
+
+	txn := hf.Begin()
+	// re-point b.example.com to a different IP: this removes it in place from
+	// the shared Hostnames line, which must not be visible on hf before Commit
+	if _, _, err := txn.AddHost("127.0.0.2", "b.example.com", ""); err != nil {
+		t.Fatalf("Txn.AddHost: %v", err)
+	}
+
+	if got := hf.HostsFileLines[idx].Hostnames; !equalStrings(got, want) {
+		t.Fatalf("parent Hostnames mutated before Commit: got %v, want %v", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
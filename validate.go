@@ -0,0 +1,27 @@
+package libhosty
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hostnameLabelRe matches a single RFC 1123 label: 1-63 characters, letters,
+// digits and hyphens, not leading or trailing with a hyphen.
+var hostnameLabelRe = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+
+// isValidHostname reports whether hostname is a valid RFC 1123 hostname: dot
+// separated labels of 1-63 allowed characters each, no more than 253
+// characters total.
+func isValidHostname(hostname string) bool {
+	if len(hostname) == 0 || len(hostname) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(hostname, ".") {
+		if !hostnameLabelRe.MatchString(label) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,72 @@
+package libhosty
+
+import "testing"
+
+func TestIsValidHostname(t *testing.T) {
+	cases := []struct {
+		hostname string
+		want     bool
+	}{
+		{"example.com", true},
+		{"sub.example.com", true},
+		{"localhost", true},
+		{"xn--80ak6aa92e.com", true},
+		{"", false},
+		{"not a hostname", false},
+		{"-example.com", false},
+		{"example-.com", false},
+		{"example..com", false},
+		{"exa#mple.com", false},
+	}
+
+	for _, c := range cases {
+		if got := isValidHostname(c.hostname); got != c.want {
+			t.Errorf("isValidHostname(%q) = %v, want %v", c.hostname, got, c.want)
+		}
+	}
+}
+
+func TestAddHostRejectsInvalidIPAndHostname(t *testing.T) {
+	hf := newTestHostsFile()
+
+	_, _, err := hf.AddHost("not-an-ip", "example.com", "")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid IP")
+	}
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+	if perr.Cause != ErrInvalidIP {
+		t.Fatalf("expected Cause ErrInvalidIP, got %v", perr.Cause)
+	}
+
+	_, _, err = hf.AddHost("127.0.0.1", "not a hostname", "")
+	if err == nil {
+		t.Fatalf("expected an error for an invalid hostname")
+	}
+	perr, ok = err.(*ParseError)
+	if !ok {
+		t.Fatalf("expected *ParseError, got %T: %v", err, err)
+	}
+	if perr.Cause != ErrInvalidHostname {
+		t.Fatalf("expected Cause ErrInvalidHostname, got %v", perr.Cause)
+	}
+}
+
+func TestAddHostRawRejectsExactDuplicate(t *testing.T) {
+	hf := newTestHostsFile()
+
+	if _, _, err := hf.AddHostRaw("127.0.0.1", "example.com", ""); err != nil {
+		t.Fatalf("first AddHostRaw: %v", err)
+	}
+
+	_, _, err := hf.AddHostRaw("127.0.0.1", "example.com", "")
+	if err != ErrDuplicateEntry {
+		t.Fatalf("expected ErrDuplicateEntry, got %v", err)
+	}
+
+	if len(hf.HostsFileLines) != 1 {
+		t.Fatalf("expected duplicate to be rejected without adding a line, got %d lines", len(hf.HostsFileLines))
+	}
+}